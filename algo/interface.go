@@ -4,3 +4,12 @@ package algo
 type TextScorer interface {
 	Compare(a, b string) float64
 }
+
+// PositionalScorer is an optional extension of TextScorer for algorithms
+// that can also report which rune positions in a took part in the match,
+// so callers can highlight them. Match should return the same score as
+// Compare(a, b) would.
+type PositionalScorer interface {
+	TextScorer
+	Match(a, b string) (score float64, positions []int)
+}
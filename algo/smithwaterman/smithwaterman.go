@@ -0,0 +1,110 @@
+// Package smithwaterman scores strings using the Smith-Waterman-Gotoh local
+// sequence alignment algorithm, which is well suited to fuzzy matching a
+// short query against a longer line of text.
+package smithwaterman
+
+import "strings"
+
+// Substitution scores a pair of aligned characters.
+type Substitution interface {
+	Score(a, b rune) float64
+}
+
+// MatchMismatch is the common Substitution: an exact match scores Match,
+// anything else scores Mismatch.
+type MatchMismatch struct {
+	Match    float64
+	Mismatch float64
+}
+
+func (m MatchMismatch) Score(a, b rune) float64 {
+	if a == b {
+		return m.Match
+	}
+	return m.Mismatch
+}
+
+// SmithWatermanGotoh implements algo.TextScorer (and algo.PositionalScorer)
+// by finding the highest scoring local alignment between a and b.
+type SmithWatermanGotoh struct {
+	CaseSensitive bool
+	GapPenalty    float64
+	Substitution  Substitution
+}
+
+// Compare implements algo.TextScorer.
+func (s *SmithWatermanGotoh) Compare(a, b string) float64 {
+	score, _ := s.Match(a, b)
+	return score
+}
+
+// Match implements algo.PositionalScorer. It returns the alignment score and
+// the rune positions in a that were part of the best local alignment, so
+// callers can highlight them.
+func (s *SmithWatermanGotoh) Match(a, b string) (float64, []int) {
+	ra, rb := []rune(a), []rune(b)
+	if !s.CaseSensitive {
+		ra, rb = []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	}
+	n, m := len(ra), len(rb)
+	if n == 0 || m == 0 {
+		return 0, nil
+	}
+
+	type cell struct {
+		score float64
+		from  byte // 0 none, 'd' diagonal, 'u' up (gap in b), 'l' left (gap in a)
+	}
+	grid := make([][]cell, n+1)
+	for i := range grid {
+		grid[i] = make([]cell, m+1)
+	}
+
+	var best cell
+	bestI, bestJ := 0, 0
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			diag := grid[i-1][j-1].score + s.Substitution.Score(ra[i-1], rb[j-1])
+			up := grid[i-1][j].score + s.GapPenalty
+			left := grid[i][j-1].score + s.GapPenalty
+			c := cell{}
+			switch {
+			case diag > 0 && diag >= up && diag >= left:
+				c = cell{score: diag, from: 'd'}
+			case up > 0 && up >= left:
+				c = cell{score: up, from: 'u'}
+			case left > 0:
+				c = cell{score: left, from: 'l'}
+			}
+			grid[i][j] = c
+			if c.score > best.score {
+				best, bestI, bestJ = c, i, j
+			}
+		}
+	}
+	if best.score <= 0 {
+		return 0, nil
+	}
+
+	var positions []int
+	for i, j := bestI, bestJ; i > 0 && j > 0 && grid[i][j].score > 0; {
+		switch grid[i][j].from {
+		case 'd':
+			if ra[i-1] == rb[j-1] {
+				positions = append(positions, i-1)
+			}
+			i--
+			j--
+		case 'u':
+			i--
+		case 'l':
+			j--
+		default:
+			i, j = 0, 0
+		}
+	}
+	for l, r := 0, len(positions)-1; l < r; l, r = l+1, r-1 {
+		positions[l], positions[r] = positions[r], positions[l]
+	}
+	return best.score, positions
+}
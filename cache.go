@@ -0,0 +1,56 @@
+package fuzzy
+
+// filterCacheEntry is one memoized Filter result.
+type filterCacheEntry struct {
+	query      string
+	items      []InputItem
+	generation int
+}
+
+// filterCache is a small LRU, most-recently-used first, keyed by the exact
+// query string passed to Content.Filter.
+type filterCache struct {
+	size    int
+	entries []*filterCacheEntry
+}
+
+func newFilterCache(size int) *filterCache {
+	if size <= 0 {
+		size = 16
+	}
+	return &filterCache{size: size}
+}
+
+// get returns the entry for query, if present, and marks it most recently
+// used.
+func (c *filterCache) get(query string) (*filterCacheEntry, bool) {
+	for i, e := range c.entries {
+		if e.query == query {
+			c.touch(i)
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// touch moves the entry at index i to the front of the LRU order.
+func (c *filterCache) touch(i int) {
+	e := c.entries[i]
+	copy(c.entries[1:i+1], c.entries[:i])
+	c.entries[0] = e
+}
+
+// put inserts or replaces entry at the front, evicting the least recently
+// used entry if the cache is over its size.
+func (c *filterCache) put(entry *filterCacheEntry) {
+	for i, e := range c.entries {
+		if e.query == entry.query {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			break
+		}
+	}
+	c.entries = append([]*filterCacheEntry{entry}, c.entries...)
+	if len(c.entries) > c.size {
+		c.entries = c.entries[:c.size]
+	}
+}
@@ -75,18 +75,62 @@ func Run(args []string, env Env, stdin *os.File, stdout, stderr io.Writer) error
 		flags.PrintDefaults()
 	}
 	verbose := flags.Bool("v", false, "verbose. print out scores with text")
+	multi := flags.Bool("m", false, "multi-select: Tab toggles a row, Enter prints all selected lines newline-separated")
+	flags.BoolVar(multi, "multi", false, "same as -m")
+	noHighlight := flags.Bool("no-highlight", false, "don't highlight matched characters in the results")
+	preview := flags.String("preview", "", "command to run for the highlighted row, {} is its value and {n} its row number")
+	previewBottom := flags.Bool("preview-bottom", false, "place the preview pane below the results instead of to the right")
+	delimiter := flags.String("delimiter", "", "regex used to split each line into fields for -nth/-with-nth")
+	nth := flags.String("nth", "", "restrict matching to these fields, e.g. \"2\", \"2,4\", \"2..\"")
+	withNth := flags.String("with-nth", "", "display only these fields, e.g. \"2\", \"2,4\", \"2..\"")
 	input := stdin
 	err := flags.Parse(args[1:])
 	if err != nil {
 		return fmt.Errorf("failed to parse flags: %w", err)
 	}
 	query := strings.Join(flags.Args(), " ")
-	content := fuzzy.ReadNewContent(input)
+	content := fuzzy.StreamNewContent(input)
 	caseSensitive := HasUpper(query)
 	content.SetTextScorer(NewSmithWaterman(caseSensitive))
 	if *verbose {
 		content.SetVerbose()
 	}
+	if *delimiter != "" {
+		if err := content.SetDelimiter(*delimiter); err != nil {
+			return fmt.Errorf("invalid -delimiter: %w", err)
+		}
+	}
+	if *nth != "" {
+		if err := content.SetSearchFields(*nth); err != nil {
+			return fmt.Errorf("invalid -nth: %w", err)
+		}
+	}
+	if *withNth != "" {
+		if err := content.SetDisplayFields(*withNth); err != nil {
+			return fmt.Errorf("invalid -with-nth: %w", err)
+		}
+	}
+	if *noHighlight {
+		content.SetHighlight(false)
+	}
+	if *preview != "" {
+		pos := fuzzy.PreviewRight
+		if *previewBottom {
+			pos = fuzzy.PreviewBottom
+		}
+		content.SetPreview(*preview, pos)
+	}
+	if *multi {
+		content.SetMulti(true, 0)
+		results, err := fuzzy.FindMulti(query, content)
+		if err != nil {
+			return err
+		}
+		for _, result := range results {
+			fmt.Fprintln(stdout, result)
+		}
+		return nil
+	}
 	out, err := fuzzy.Find(query, content)
 	fmt.Fprintln(stdout, out)
 	return err
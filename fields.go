@@ -0,0 +1,191 @@
+package fuzzy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fieldRange is a single 1-based inclusive range parsed from a field spec.
+// A zero bound means "unbounded" (start defaults to the first field, end to
+// the last); negative bounds count back from the last field.
+type fieldRange struct {
+	start, end int
+}
+
+// fieldSpec is a comma-separated list of fieldRanges, as accepted by
+// Content.SetSearchFields / Content.SetDisplayFields.
+type fieldSpec []fieldRange
+
+// parseFieldSpec parses a spec like "2", "2,4", "-1", "2..", "..3" or "2..4".
+// It returns an error if any comma-separated part isn't a valid integer or
+// range, rather than silently treating it as the unbounded range "..".
+func parseFieldSpec(spec string) (fieldSpec, error) {
+	var fs fieldSpec
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r := fieldRange{}
+		if i := strings.Index(part, ".."); i >= 0 {
+			if start := part[:i]; start != "" {
+				n, err := strconv.Atoi(start)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field spec %q: %w", part, err)
+				}
+				r.start = n
+			}
+			if end := part[i+2:]; end != "" {
+				n, err := strconv.Atoi(end)
+				if err != nil {
+					return nil, fmt.Errorf("invalid field spec %q: %w", part, err)
+				}
+				r.end = n
+			}
+		} else {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid field spec %q: %w", part, err)
+			}
+			r.start, r.end = n, n
+		}
+		fs = append(fs, r)
+	}
+	return fs, nil
+}
+
+// resolve expands fs into 1-based field indices against a line with n
+// fields, in spec order. Out-of-range indices are dropped.
+func (fs fieldSpec) resolve(n int) []int {
+	var idx []int
+	for _, r := range fs {
+		start, end := r.start, r.end
+		if start == 0 {
+			start = 1
+		} else if start < 0 {
+			start = n + start + 1
+		}
+		if end == 0 {
+			end = n
+		} else if end < 0 {
+			end = n + end + 1
+		}
+		if start < 1 {
+			start = 1
+		}
+		if end > n {
+			end = n
+		}
+		for i := start; i <= end; i++ {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// SetDelimiter configures the regular expression used to split each line
+// into fields for SetSearchFields/SetDisplayFields. The default, if never
+// called, splits on runs of whitespace.
+func (c *Content) SetDelimiter(regex string) error {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delimiter = re
+	c.clearFieldCache()
+	return nil
+}
+
+// SetSearchFields restricts scoring to the given fields of each line (see
+// fieldSpec for the spec grammar), rather than the whole line. Value()
+// still returns the untouched original line.
+func (c *Content) SetSearchFields(spec string) error {
+	fs, err := parseFieldSpec(spec)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.searchFields = fs
+	c.clearFieldCache()
+	return nil
+}
+
+// SetDisplayFields restricts what's shown in the results table to the given
+// fields of each line, while Value() still returns the untouched original
+// line and scoring still uses SetSearchFields (or the whole line).
+func (c *Content) SetDisplayFields(spec string) error {
+	fs, err := parseFieldSpec(spec)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.displayFields = fs
+	c.clearFieldCache()
+	return nil
+}
+
+// clearFieldCache drops any cached per-item field tokenization; callers
+// must hold c.mu.
+func (c *Content) clearFieldCache() {
+	for i := range c.data {
+		c.data[i].fields = nil
+	}
+	c.generation++
+}
+
+// tokenize splits line into fields using c.delimiter, or whitespace if none
+// was set. Callers must hold c.mu.
+func (c *Content) tokenize(line string) []string {
+	if c.delimiter == nil {
+		return strings.Fields(line)
+	}
+	return c.delimiter.Split(line, -1)
+}
+
+// itemFields returns item's cached field tokenization, computing and
+// caching it on first use. Callers must hold c.mu.
+func (c *Content) itemFields(item *InputItem) []string {
+	if item.fields == nil {
+		item.fields = c.tokenize(item.item.String())
+	}
+	return item.fields
+}
+
+// sameFieldProjection reports whether searchFields and displayFields select
+// the same fields, so that match positions (computed against the search
+// projection in Filter) can be painted directly onto the display
+// projection in GetCell. Callers must hold c.mu.
+func (c *Content) sameFieldProjection() bool {
+	if len(c.searchFields) != len(c.displayFields) {
+		return false
+	}
+	for i, r := range c.searchFields {
+		if r != c.displayFields[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldProjection joins the fields of item selected by spec with a space.
+// An empty spec means "the whole line". Callers must hold c.mu.
+func (c *Content) fieldProjection(item *InputItem, spec fieldSpec) string {
+	if len(spec) == 0 {
+		return item.item.String()
+	}
+	fields := c.itemFields(item)
+	idx := spec.resolve(len(fields))
+	selected := make([]string, 0, len(idx))
+	for _, n := range idx {
+		if n >= 1 && n <= len(fields) {
+			selected = append(selected, fields[n-1])
+		}
+	}
+	return strings.Join(selected, " ")
+}
@@ -5,9 +5,16 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"regexp"
 	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/bottlerocketlabs/fuzzy/algo"
+	qry "github.com/bottlerocketlabs/fuzzy/query"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -21,8 +28,11 @@ type ValueStringer interface {
 
 // InputItem in an item of ValueStringer with a Score
 type InputItem struct {
-	item  ValueStringer
-	Score float64
+	item      ValueStringer
+	Score     float64
+	id        int
+	positions []int
+	fields    []string
 }
 
 func NewInputItem(item ValueStringer) InputItem {
@@ -60,6 +70,30 @@ type Content struct {
 	verbose         bool
 	hideLessThan    float64
 	returnOneResult bool
+	querySyntax     bool
+	multi           bool
+	multiMax        int
+	selected        map[int]bool
+	highlight       bool
+	highlightColor  tcell.Color
+
+	previewCmdTemplate string
+	previewPos         PreviewPos
+	previewEnabled     bool
+	previewByteLimit   int
+
+	delimiter     *regexp.Regexp
+	searchFields  fieldSpec
+	displayFields fieldSpec
+
+	mu             sync.Mutex
+	streaming      bool
+	done           chan struct{}
+	lastQuery      string
+	lastGeneration int
+	generation     int
+	cache          *filterCache
+	onUpdate       func()
 }
 
 type NopScorer struct{}
@@ -72,8 +106,10 @@ func (NopScorer) Compare(a, b string) float64 {
 func SupplyNewContent(input []ValueStringer) *Content {
 	ts := NopScorer{}
 	data := []InputItem{}
-	for _, item := range input {
-		data = append(data, NewInputItem(item))
+	for id, item := range input {
+		ii := NewInputItem(item)
+		ii.id = id
+		data = append(data, ii)
 	}
 	c := Content{
 		scorer:          ts,
@@ -81,31 +117,129 @@ func SupplyNewContent(input []ValueStringer) *Content {
 		live:            data,
 		hideLessThan:    1,
 		returnOneResult: false,
+		querySyntax:     true,
+		highlight:       true,
+		highlightColor:  tcell.ColorRed,
+		cache:           newFilterCache(16),
 	}
 	return &c
 }
 
-// ReadNewContent creates a new Content from new line separated input
+// ReadNewContent creates a new Content from new line separated input. It
+// blocks until input is fully drained; use StreamNewContent to let the UI
+// open while slow input is still being read.
 func ReadNewContent(input io.Reader) *Content {
-	ts := NopScorer{}
-	data := []InputItem{}
+	c := StreamNewContent(input)
+	<-c.done
+	return c
+}
+
+// StreamNewContent creates a new Content and starts reading newline
+// separated input in the background, so the caller can open the UI (via
+// Find/FindWithScreen) before input finishes arriving. Lines are appended
+// to the Content as they are read; Find repaints the table and shows a
+// matched/total count for as long as reading is in progress.
+func StreamNewContent(input io.Reader) *Content {
+	c := &Content{
+		scorer:         NopScorer{},
+		hideLessThan:   1,
+		querySyntax:    true,
+		highlight:      true,
+		highlightColor: tcell.ColorRed,
+		cache:          newFilterCache(16),
+		streaming:      true,
+		done:           make(chan struct{}),
+	}
+	go c.stream(input)
+	return c
+}
+
+// streamUpdateInterval caps how often stream notifies c.onUpdate while
+// input is still arriving, so a fast/large pipeline doesn't trigger a full
+// re-filter and redraw per line.
+const streamUpdateInterval = 75 * time.Millisecond
+
+// stream reads lines from input into c.data/c.live under lock, notifying
+// c.onUpdate (if set) at most once per streamUpdateInterval while reading,
+// and once more, unconditionally, when reading finishes.
+func (c *Content) stream(input io.Reader) {
+	defer close(c.done)
 	scanner := bufio.NewScanner(input)
+	var lastUpdate time.Time
 	for scanner.Scan() {
-		data = append(data, NewInputItem(NewStr(scanner.Text())))
+		ii := NewInputItem(NewStr(scanner.Text()))
+		c.mu.Lock()
+		ii.id = len(c.data)
+		c.data = append(c.data, ii)
+		c.live = c.data
+		c.generation++
+		var update func()
+		if now := time.Now(); now.Sub(lastUpdate) >= streamUpdateInterval {
+			lastUpdate = now
+			update = c.onUpdate
+		}
+		c.mu.Unlock()
+		if update != nil {
+			update()
+		}
 	}
-	c := Content{
-		scorer:          ts,
-		data:            data,
-		live:            data,
-		hideLessThan:    1,
-		returnOneResult: false,
+	c.mu.Lock()
+	c.streaming = false
+	update := c.onUpdate
+	c.mu.Unlock()
+	if update != nil {
+		update()
 	}
-	return &c
+}
+
+// liveValue returns the Value() of the live row at the given index.
+func (c *Content) liveValue(row int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if row < 0 || row >= len(c.live) {
+		return "", false
+	}
+	return c.live[row].item.Value(), true
+}
+
+// setOnUpdate registers the callback that stream invokes while lines arrive
+// (at most once per streamUpdateInterval) and once more when streaming
+// finishes.
+func (c *Content) setOnUpdate(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onUpdate = fn
+}
+
+// currentQuery returns the query passed to the most recent Filter call.
+func (c *Content) currentQuery() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastQuery
+}
+
+// streamStatus reports the total items ingested so far, how many currently
+// match the live filter, and whether the background reader is still active.
+func (c *Content) streamStatus() (total, matched int, streaming bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.data), len(c.live), c.streaming
 }
 
 // SetTextScorer sets the algorithm for scoring the query against the line
 func (c *Content) SetTextScorer(textScorer algo.TextScorer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.scorer = textScorer
+	c.generation++
+}
+
+// SetCacheSize bounds how many past queries' Filter results are memoized to
+// speed up incremental typing. The default is 16.
+func (c *Content) SetCacheSize(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache = newFilterCache(n)
 }
 
 // SetVerbose outputs the scores along with the line. useful for debugging
@@ -120,49 +254,244 @@ func (c *Content) SetReturnOneResult() {
 
 // SetHideLessThan remove item from output with a lower score
 func (c *Content) SetHideLessThan(score float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.hideLessThan = score
+	c.generation++
+}
+
+// SetQuerySyntax toggles the extended query syntax (AND/OR groups,
+// exact/prefix/suffix matches and negation, see package query). It is
+// enabled by default; pass false to fall back to scoring the whole query
+// as a single fuzzy term against the configured algo.TextScorer.
+func (c *Content) SetQuerySyntax(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.querySyntax = enabled
+	c.generation++
+}
+
+// SetHighlight toggles highlighting of matched characters in the results
+// table. It is enabled by default. Exact, prefix and suffix terms of the
+// extended query syntax are always highlighted; fuzzy terms are highlighted
+// only when the configured algo.TextScorer also implements
+// algo.PositionalScorer. Nothing is highlighted when the display fields
+// differ from the search fields.
+func (c *Content) SetHighlight(enabled bool) {
+	c.highlight = enabled
+}
+
+// SetHighlightColor sets the color used to highlight matched characters.
+func (c *Content) SetHighlightColor(color tcell.Color) {
+	c.highlightColor = color
+}
+
+// SetMulti enables multi-select mode, where Tab toggles the highlighted row
+// and Enter returns every selected row (see FindMulti). max caps the number
+// of rows that can be selected at once; 0 means unlimited.
+func (c *Content) SetMulti(enabled bool, max int) {
+	c.multi = enabled
+	c.multiMax = max
+}
+
+// toggleSelected flips the selection state of the given row in c.live.
+func (c *Content) toggleSelected(row int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if row < 0 || row >= len(c.live) {
+		return
+	}
+	id := c.live[row].id
+	if c.selected == nil {
+		c.selected = map[int]bool{}
+	}
+	if c.selected[id] {
+		delete(c.selected, id)
+		return
+	}
+	if c.multiMax > 0 && len(c.selected) >= c.multiMax {
+		return
+	}
+	c.selected[id] = true
+}
+
+// selectedValues returns the Value() of every selected item, or, if nothing
+// is selected, the highlighted row's Value().
+func (c *Content) selectedValues(row int) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.selected) == 0 {
+		if row < 0 || row >= len(c.live) {
+			return nil
+		}
+		return []string{c.live[row].item.Value()}
+	}
+	out := make([]string, 0, len(c.selected))
+	for _, item := range c.data {
+		if c.selected[item.id] {
+			out = append(out, item.item.Value())
+		}
+	}
+	return out
 }
 
 func (c *Content) GetCell(row, column int) *tview.TableCell {
-	if 0 > row || row > c.GetRowCount() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if 0 > row || row > len(c.live) {
 		return nil
 	}
-	r := c.live[row]
+	r := &c.live[row]
+	if c.multi {
+		if column == 0 {
+			marker := " "
+			if c.selected[r.id] {
+				marker = "*"
+			}
+			return tview.NewTableCell(marker)
+		}
+	}
+	text := c.fieldProjection(r, c.displayFields)
+	if c.highlight && c.sameFieldProjection() {
+		text = highlightPositions(text, r.positions, c.highlightColor)
+	}
 	if c.verbose {
-		return tview.NewTableCell(fmt.Sprintf("%s [%f]", r.item.String(), r.Score))
+		return tview.NewTableCell(fmt.Sprintf("%s [%f]", text, r.Score))
 	}
-	return tview.NewTableCell(r.item.String())
+	return tview.NewTableCell(text)
+}
+
+// highlightPositions wraps the runes of line at the given positions in
+// tview color tags so they render in color.
+func highlightPositions(line string, positions []int, color tcell.Color) string {
+	if len(positions) == 0 {
+		return line
+	}
+	marked := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		marked[p] = true
+	}
+	colorTag := fmt.Sprintf("[#%06x]", color.Hex())
+	var b strings.Builder
+	for i, r := range []rune(line) {
+		if marked[i] {
+			b.WriteString(colorTag)
+			b.WriteRune(r)
+			b.WriteString("[-]")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
 }
 
 func (c *Content) GetColumnCount() int {
+	if c.multi {
+		return 2
+	}
 	return 1
 }
 
 func (c *Content) GetRowCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return len(c.live)
 }
 
+// addsWholeGroups reports whether query extends prev by appending one or
+// more complete space-separated groups, rather than by adding characters to
+// prev's last group. The boundary must land on whitespace: either prev
+// already ends in whitespace, or the first rune query adds after prev is
+// whitespace.
+func addsWholeGroups(prev, query string) bool {
+	if prev == "" || query == prev || !strings.HasPrefix(query, prev) {
+		return false
+	}
+	if last, _ := utf8.DecodeLastRuneInString(prev); unicode.IsSpace(last) {
+		return true
+	}
+	added, _ := utf8.DecodeRuneInString(query[len(prev):])
+	return unicode.IsSpace(added)
+}
+
 // Filter processes InputItems, scores them with SmithWaterman
 // Any items with score less than 1 are not shown
 // Items are sorted by their score
 func (c *Content) Filter(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if query == "" {
+		c.lastQuery = ""
+		c.lastGeneration = c.generation
 		c.live = c.data
 		return
 	}
+	if entry, ok := c.cache.get(query); ok && entry.generation == c.generation {
+		c.lastQuery = query
+		c.lastGeneration = c.generation
+		c.live = entry.items
+		return
+	}
+
+	// Narrowing to c.live is only sound when query adds one or more whole
+	// new AND groups on top of c.lastQuery, unchanged: the old groups are
+	// re-evaluated verbatim below, and AND semantics mean a row that failed
+	// them before still fails them now, so only rows already in c.live can
+	// possibly match. Narrowing on a query that merely extends the *last*
+	// group's characters (e.g. "a" -> "ab") would not be sound -- the
+	// Smith-Waterman scorer isn't monotonic under term extension (a newly
+	// typed character can start a better local alignment than the previous
+	// query found) -- so that case falls through to a full rescan.
+	base := c.data
+	if c.querySyntax && c.lastGeneration == c.generation && addsWholeGroups(c.lastQuery, query) {
+		base = c.live
+	}
+
 	live := SortableInputItems{
 		items: []InputItem{},
 		query: query,
 	}
-	for _, item := range c.data {
-		item.Score = c.scorer.Compare(item.item.String(), query)
-		if item.Score < c.hideLessThan {
-			continue
+	positional, _ := c.scorer.(algo.PositionalScorer)
+	if c.querySyntax {
+		matcher := qry.Parse(query)
+		for i := range base {
+			searchText := c.fieldProjection(&base[i], c.searchFields)
+			item := base[i]
+			score, positions, ok := matcher.Match(searchText, c.scorer)
+			if !ok {
+				continue
+			}
+			// Unlike a bare fuzzy Compare score, this total can be a
+			// legitimate 0 -- a satisfied negation (e.g. a pure "!test"
+			// query) contributes nothing by design. Matcher.Match's ok
+			// already means every AND group had a satisfying predicate, so
+			// c.hideLessThan, which exists to hide weak fuzzy matches,
+			// doesn't apply here.
+			item.Score = score
+			item.positions = positions
+			live.items = append(live.items, item)
+		}
+	} else {
+		for i := range base {
+			searchText := c.fieldProjection(&base[i], c.searchFields)
+			item := base[i]
+			if positional != nil {
+				item.Score, item.positions = positional.Match(searchText, query)
+			} else {
+				item.Score = c.scorer.Compare(searchText, query)
+			}
+			if item.Score < c.hideLessThan {
+				continue
+			}
+			live.items = append(live.items, item)
 		}
-		live.items = append(live.items, item)
 	}
 	sort.Sort(sort.Reverse(live))
 	//sort.Sort(live)
+
+	c.cache.put(&filterCacheEntry{query: query, items: live.items, generation: c.generation})
+	c.lastQuery = query
+	c.lastGeneration = c.generation
 	c.live = live.items
 }
 
@@ -184,6 +513,22 @@ func Find(query string, content *Content) (string, error) {
 
 // FindWithScreen is the same as Find, but you provide the Screen
 func FindWithScreen(screen tcell.Screen, query string, content *Content) (string, error) {
+	out, err := findWithScreen(screen, query, content)
+	if err != nil || len(out) == 0 {
+		return "", err
+	}
+	return out[0], nil
+}
+
+// FindMulti is like Find, but content must have multi-select enabled via
+// Content.SetMulti: Tab toggles the highlighted row and Enter returns every
+// selected row's Value(), falling back to the highlighted row if nothing
+// was toggled.
+func FindMulti(query string, content *Content) ([]string, error) {
+	return findWithScreen(nil, query, content)
+}
+
+func findWithScreen(screen tcell.Screen, query string, content *Content) ([]string, error) {
 	app := tview.NewApplication().SetScreen(screen)
 	table := tview.NewTable().
 		SetBorders(false).
@@ -209,32 +554,108 @@ func FindWithScreen(screen tcell.Screen, query string, content *Content) (string
 		case tcell.KeyEscape:
 			tableInputSend(event, nil)
 			return nil
+		case tcell.KeyTab, tcell.KeyBacktab:
+			if content.multi {
+				row, _ := table.GetSelection()
+				content.toggleSelected(row)
+			}
+			return nil
 		}
 		return event
 	})
 
 	content.Filter(query)
 	if content.GetRowCount() == 1 && content.returnOneResult {
-		return content.live[0].item.Value(), nil
+		if value, ok := content.liveValue(0); ok {
+			return []string{value}, nil
+		}
 	}
 	inputField.SetText(query)
-	grid := tview.NewGrid().
-		SetRows(0, 1).
-		SetColumns(0).
-		SetBorders(false).
-		AddItem(table, 0, 0, 1, 1, 0, 0, false).
-		AddItem(inputField, 1, 0, 1, 1, 0, 0, true)
-	var output string
+
+	var grid *tview.Grid
+	if enabled, pos, limit := content.previewSettings(); enabled {
+		previewView := tview.NewTextView().SetDynamicColors(true).SetWrap(true).SetScrollable(true)
+		runner := newPreviewRunner(app, previewView, limit)
+		var debounce *time.Timer
+		triggerPreview := func(row int) {
+			if debounce != nil {
+				debounce.Stop()
+			}
+			debounce = time.AfterFunc(50*time.Millisecond, func() {
+				if cmdStr, ok := content.previewCommand(row); ok {
+					runner.run(cmdStr)
+				}
+			})
+		}
+		table.SetSelectionChangedFunc(func(row, column int) {
+			triggerPreview(row)
+		})
+		switch pos {
+		case PreviewBottom:
+			grid = tview.NewGrid().
+				SetRows(0, 0, 1).
+				SetColumns(0).
+				SetBorders(false).
+				AddItem(table, 0, 0, 1, 1, 0, 0, false).
+				AddItem(previewView, 1, 0, 1, 1, 0, 0, false).
+				AddItem(inputField, 2, 0, 1, 1, 0, 0, true)
+		default:
+			grid = tview.NewGrid().
+				SetRows(0, 1).
+				SetColumns(0, 0).
+				SetBorders(false).
+				AddItem(table, 0, 0, 1, 1, 0, 0, false).
+				AddItem(previewView, 0, 1, 1, 1, 0, 0, false).
+				AddItem(inputField, 1, 0, 1, 2, 0, 0, true)
+		}
+		triggerPreview(0)
+	} else {
+		grid = tview.NewGrid().
+			SetRows(0, 1).
+			SetColumns(0).
+			SetBorders(false).
+			AddItem(table, 0, 0, 1, 1, 0, 0, false).
+			AddItem(inputField, 1, 0, 1, 1, 0, 0, true)
+	}
+
+	spinnerFrames := []rune{'|', '/', '-', '\\'}
+	spinnerIdx := 0
+	updateLabel := func() {
+		total, matched, streaming := content.streamStatus()
+		if !streaming {
+			inputField.SetLabel("> ")
+			return
+		}
+		spinnerIdx = (spinnerIdx + 1) % len(spinnerFrames)
+		inputField.SetLabel(fmt.Sprintf("%c %d/%d > ", spinnerFrames[spinnerIdx], matched, total))
+	}
+	if _, _, streaming := content.streamStatus(); streaming {
+		content.setOnUpdate(func() {
+			app.QueueUpdateDraw(func() {
+				content.Filter(content.currentQuery())
+				updateLabel()
+			})
+		})
+		// Reading may have finished between the streamStatus check above
+		// and setOnUpdate registering our callback, in which case stream's
+		// own final notification already fired into the void and nothing
+		// will call onUpdate again. Re-filter unconditionally so the last
+		// lines ingested aren't stuck unfiltered until the next keypress.
+		content.Filter(content.currentQuery())
+		updateLabel()
+	}
+
+	var output []string
 	table.Select(0, 0).SetDoneFunc(func(key tcell.Key) {
 		if key == tcell.KeyEscape {
 			app.Stop()
 		}
 	}).SetSelectedFunc(func(row int, column int) {
-		output = content.live[row].item.Value()
+		output = content.selectedValues(row)
 		app.Stop()
 	})
 	if err := app.SetRoot(grid, true).EnableMouse(true).Run(); err != nil {
-		return "", err
+		return nil, err
 	}
 	return output, nil
 }
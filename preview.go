@@ -0,0 +1,165 @@
+package fuzzy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rivo/tview"
+)
+
+// PreviewPos chooses where the preview pane is placed relative to the
+// results table.
+type PreviewPos int
+
+const (
+	PreviewRight PreviewPos = iota
+	PreviewBottom
+)
+
+const defaultPreviewByteLimit = 64 * 1024
+
+// SetPreview enables a preview pane that runs cmdTemplate (via "sh -c")
+// against the highlighted row whenever the selection changes. {} in
+// cmdTemplate is replaced by the row's Value(), single-quoted, and {n} by
+// its 1-based row index (not quoted, as it's always a plain integer), the
+// way fzf's --preview does.
+func (c *Content) SetPreview(cmdTemplate string, position PreviewPos) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.previewCmdTemplate = cmdTemplate
+	c.previewPos = position
+	c.previewEnabled = true
+	if c.previewByteLimit == 0 {
+		c.previewByteLimit = defaultPreviewByteLimit
+	}
+}
+
+// SetPreviewByteLimit caps how much preview output is kept and displayed.
+func (c *Content) SetPreviewByteLimit(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.previewByteLimit = n
+}
+
+// previewSettings reports whether a preview pane is enabled and, if so, its
+// position and byte budget.
+func (c *Content) previewSettings() (enabled bool, pos PreviewPos, limit int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.previewEnabled, c.previewPos, c.previewByteLimit
+}
+
+// previewCommand builds the shell command for the live row at index row.
+func (c *Content) previewCommand(row int) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.previewEnabled || row < 0 || row >= len(c.live) {
+		return "", false
+	}
+	value := c.live[row].item.Value()
+	cmd := strings.ReplaceAll(c.previewCmdTemplate, "{}", shellQuote(value))
+	cmd = strings.ReplaceAll(cmd, "{n}", strconv.Itoa(row+1))
+	return cmd, true
+}
+
+// shellQuote single-quotes s for safe interpolation into a "sh -c" string,
+// the way fzf quotes {} substitutions: row values are arbitrary data (e.g.
+// filenames), not trusted shell fragments, so they must never be spliced in
+// unquoted.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// previewRunner drives the preview pane: it runs one command at a time for
+// the currently selected row, cancelling whatever is in flight whenever the
+// selection moves on again before the command finishes.
+type previewRunner struct {
+	app   *tview.Application
+	view  *tview.TextView
+	limit int
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func newPreviewRunner(app *tview.Application, view *tview.TextView, limit int) *previewRunner {
+	return &previewRunner{app: app, view: view, limit: limit}
+}
+
+// run cancels any in-flight preview and starts cmdStr, streaming its
+// combined stdout/stderr into the preview pane as it arrives.
+func (p *previewRunner) run(cmdStr string) {
+	p.mu.Lock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.mu.Unlock()
+
+	p.app.QueueUpdateDraw(func() {
+		p.view.Clear()
+	})
+
+	go func() {
+		cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
+		out := &previewWriter{runner: p, limit: p.limit}
+		cmd.Stdout = out
+		cmd.Stderr = out
+		_ = cmd.Run()
+	}()
+}
+
+// stop cancels any in-flight preview command.
+func (p *previewRunner) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+// previewWriter feeds command output into the preview pane, capping total
+// bytes at limit and cancelling the command once that budget is spent.
+type previewWriter struct {
+	runner *previewRunner
+	limit  int
+
+	mu      sync.Mutex
+	written int
+	done    bool
+}
+
+func (w *previewWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return 0, io.ErrClosedPipe
+	}
+	chunk := p
+	capped := false
+	if w.limit > 0 && w.written+len(chunk) > w.limit {
+		chunk = chunk[:w.limit-w.written]
+		w.done = true
+		capped = true
+	}
+	w.written += len(chunk)
+	w.mu.Unlock()
+
+	text := tview.Escape(string(chunk))
+	if capped {
+		text += "\n[…truncated]"
+	}
+	w.runner.app.QueueUpdateDraw(func() {
+		fmt.Fprint(w.runner.view, text)
+	})
+	if capped {
+		w.runner.stop()
+	}
+	return len(p), nil
+}
@@ -0,0 +1,186 @@
+// Package query implements an fzf-style extended query syntax on top of a
+// fuzzy.Content's configured algo.TextScorer.
+//
+// A query is a space-separated list of AND groups. Within a group, terms
+// separated by `|` are OR'd together. Each term is either a plain fuzzy
+// term (scored via the configured algo.TextScorer) or one of:
+//
+//	'foo   exact substring match
+//	^foo   prefix match
+//	foo$   suffix match
+//
+// Any of the above, including plain fuzzy terms, can be negated with a
+// leading `!`.
+package query
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bottlerocketlabs/fuzzy/algo"
+)
+
+// exactBonus is the fixed score contributed by a matched exact/prefix/suffix
+// predicate, in the same rough range as algo.TextScorer implementations.
+const exactBonus = 10
+
+// Kind identifies how a Predicate's term should be matched against an item.
+type Kind int
+
+const (
+	KindFuzzy Kind = iota
+	KindExact
+	KindPrefix
+	KindSuffix
+)
+
+// Predicate is a single match condition, optionally negated.
+type Predicate struct {
+	Kind   Kind
+	Term   string
+	Negate bool
+}
+
+// Match reports whether item satisfies the predicate, the score it
+// contributes when it does, and the rune positions in item its bare Term
+// (not the raw query, which may still carry `'`/`^`/`$`/`!`) matched at.
+// scorer is used for KindFuzzy predicates only, and must implement
+// algo.PositionalScorer to get positions back for them. Negated and
+// non-matching predicates never report positions.
+func (p Predicate) Match(item string, scorer algo.TextScorer) (score float64, positions []int, ok bool) {
+	var matched bool
+	switch p.Kind {
+	case KindExact:
+		if i := strings.Index(item, p.Term); i >= 0 {
+			matched = true
+			positions = runePositions(item, i, len(p.Term))
+		}
+	case KindPrefix:
+		if strings.HasPrefix(item, p.Term) {
+			matched = true
+			positions = runePositions(item, 0, len(p.Term))
+		}
+	case KindSuffix:
+		if strings.HasSuffix(item, p.Term) {
+			matched = true
+			positions = runePositions(item, len(item)-len(p.Term), len(p.Term))
+		}
+	default:
+		if positional, isPositional := scorer.(algo.PositionalScorer); isPositional {
+			score, positions = positional.Match(item, p.Term)
+		} else {
+			score = scorer.Compare(item, p.Term)
+		}
+		matched = score > 0
+	}
+	if p.Kind != KindFuzzy && matched {
+		score = exactBonus
+	}
+	if p.Negate {
+		return 0, nil, !matched
+	}
+	if !matched {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+// runePositions converts the byte range [byteStart, byteStart+byteLen) of s
+// into the list of rune indices it spans.
+func runePositions(s string, byteStart, byteLen int) []int {
+	if byteLen <= 0 {
+		return nil
+	}
+	byteEnd := byteStart + byteLen
+	var positions []int
+	runeIdx, byteIdx := 0, 0
+	for _, r := range s {
+		if byteIdx >= byteStart && byteIdx < byteEnd {
+			positions = append(positions, runeIdx)
+		}
+		byteIdx += utf8.RuneLen(r)
+		runeIdx++
+	}
+	return positions
+}
+
+// Group is a set of predicates evaluated with OR: it is satisfied if any
+// predicate in it matches.
+type Group []Predicate
+
+// Matcher is a compiled query: a list of Groups evaluated with AND.
+type Matcher struct {
+	groups []Group
+}
+
+// Parse tokenizes input into a Matcher. An empty input matches everything.
+func Parse(input string) Matcher {
+	fields := strings.Fields(input)
+	groups := make([]Group, 0, len(fields))
+	for _, field := range fields {
+		terms := strings.Split(field, "|")
+		group := make(Group, 0, len(terms))
+		for _, term := range terms {
+			group = append(group, parsePredicate(term))
+		}
+		groups = append(groups, group)
+	}
+	return Matcher{groups: groups}
+}
+
+func parsePredicate(term string) Predicate {
+	p := Predicate{}
+	if strings.HasPrefix(term, "!") {
+		p.Negate = true
+		term = term[1:]
+	}
+	switch {
+	case strings.HasPrefix(term, "'"):
+		p.Kind = KindExact
+		term = term[1:]
+	case strings.HasPrefix(term, "^"):
+		p.Kind = KindPrefix
+		term = term[1:]
+	case strings.HasSuffix(term, "$"):
+		p.Kind = KindSuffix
+		term = term[:len(term)-1]
+	default:
+		p.Kind = KindFuzzy
+	}
+	p.Term = term
+	return p
+}
+
+// Match scores item against every group, summing the best score from each
+// and unioning the positions of each group's best-scoring predicate. ok is
+// false if any AND group has no matching predicate (or, for a negation,
+// its negated predicate matches).
+func (m Matcher) Match(item string, scorer algo.TextScorer) (score float64, positions []int, ok bool) {
+	if len(m.groups) == 0 {
+		return 0, nil, true
+	}
+	var total float64
+	var matchedPositions []int
+	for _, group := range m.groups {
+		matched := false
+		var best float64
+		var bestPositions []int
+		for _, pred := range group {
+			s, pos, ok := pred.Match(item, scorer)
+			if !ok {
+				continue
+			}
+			matched = true
+			if s > best {
+				best = s
+				bestPositions = pos
+			}
+		}
+		if !matched {
+			return 0, nil, false
+		}
+		total += best
+		matchedPositions = append(matchedPositions, bestPositions...)
+	}
+	return total, matchedPositions, true
+}